@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const deployDecisionAction = "deploy-decision"
+
+// quickDeployEnvironment is the target environment for deploys requested
+// through the ephemeral deploy-quick flow, which has no environment
+// selector of its own.
+const quickDeployEnvironment = "production"
+
+// ApprovalCoordinator gates a deploy behind sign-off from an authorized set
+// of approvers before handing it to deployer, and records an audit trail of
+// who approved what. It replaces the direct confirm-deployment -> deploy
+// path with confirm-deployment -> approval request -> deploy.
+type ApprovalCoordinator struct {
+	store     Store
+	approvers ApproverSource
+	audit     AuditSink
+	deployer  Deployer
+	jobs      *jobLocationStore
+	channel   string
+	required  int
+
+	mu   sync.Mutex
+	next int
+}
+
+func NewApprovalCoordinator(store Store, approvers ApproverSource, audit AuditSink, deployer Deployer, jobs *jobLocationStore, channel string, required int) *ApprovalCoordinator {
+	return &ApprovalCoordinator{
+		store:     store,
+		approvers: approvers,
+		audit:     audit,
+		deployer:  deployer,
+		jobs:      jobs,
+		channel:   channel,
+		required:  required,
+	}
+}
+
+// RequestApproval handles a "Do it" click from the ephemeral deploy-quick
+// flow: instead of deploying directly, it records a pending ApprovalRequest
+// and posts it to the approval channel for an authorized approver to act
+// on.
+func (c *ApprovalCoordinator) RequestApproval(ctx *ActionContext) error {
+	action := ctx.Action
+	responder := ctx.Responder
+
+	if !strings.HasPrefix(action.Value, "v") {
+		return responder.DeleteOriginal()
+	}
+	version := action.Value
+	requester := ctx.Payload.User.ID
+
+	if err := responder.DeleteOriginal(); err != nil {
+		return err
+	}
+
+	return c.requestApproval(responder, ctx.Payload.Channel.ID, requester, version, quickDeployEnvironment)
+}
+
+// RequestModalApproval is the modal-submission equivalent of
+// RequestApproval: the deploy form's own submit button is already the
+// confirmation step, so there's no confirmation message to delete first.
+func (c *ApprovalCoordinator) RequestModalApproval(ctx *ViewSubmissionContext, requester, version, environment string) error {
+	return c.requestApproval(ctx.Responder, ctx.View.PrivateMetadata, requester, version, environment)
+}
+
+func (c *ApprovalCoordinator) requestApproval(responder Responder, channel, requester, version, environment string) error {
+	req := &ApprovalRequest{
+		ID:          c.newRequestID(),
+		Version:     version,
+		Environment: environment,
+		Requester:   requester,
+		Channel:     channel,
+		Required:    c.required,
+		CreatedAt:   time.Now(),
+	}
+	if err := c.store.Create(req); err != nil {
+		return err
+	}
+
+	ts, err := responder.WithChannel(c.channel).Post(approvalRequestMessage(req))
+	if err != nil {
+		return err
+	}
+	return c.store.SetMessageTS(req.ID, ts)
+}
+
+func (c *ApprovalCoordinator) newRequestID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id := fmt.Sprintf("approval-%d", c.next)
+	c.next++
+	return id
+}
+
+// HandleDecision handles a click on the Approve or Deny button of a pending
+// approval request. Both buttons share deployDecisionAction's BlockID, the
+// same way the ephemeral confirm-deployment message's "Do it"/"Stop"
+// buttons do, and are told apart by action.Value's "approve:"/"deny:"
+// prefix.
+func (c *ApprovalCoordinator) HandleDecision(ctx *ActionContext) error {
+	verb, id, ok := strings.Cut(ctx.Action.Value, ":")
+	if !ok {
+		return fmt.Errorf("approval: malformed decision value %q", ctx.Action.Value)
+	}
+
+	switch verb {
+	case "approve":
+		return c.handleApprove(ctx, id)
+	case "deny":
+		return c.handleDeny(ctx, id)
+	default:
+		return fmt.Errorf("approval: unknown decision %q", verb)
+	}
+}
+
+// handleApprove records the clicking user's approval, if they're authorized,
+// and starts the deploy once enough distinct approvers have signed off.
+func (c *ApprovalCoordinator) handleApprove(ctx *ActionContext, id string) error {
+	approver := ctx.Payload.User.ID
+
+	pending, err := c.store.Get(id)
+	if err != nil {
+		return err
+	}
+	if pending.Outcome != "" {
+		// Already decided, e.g. by the approver whose click crossed
+		// Required before this one landed. Don't re-decide or redeploy.
+		return nil
+	}
+	if approver == pending.Requester {
+		return ctx.Responder.PostEphemeral(slack.MsgOptionText(
+			fmt.Sprintf("<@%s> can't approve their own deploy.", approver), false))
+	}
+
+	allowed, err := c.approvers.Approvers(context.Background())
+	if err != nil {
+		return err
+	}
+	if !allowed[approver] {
+		return ctx.Responder.PostEphemeral(slack.MsgOptionText(
+			fmt.Sprintf("<@%s> isn't authorized to approve deploys.", approver), false))
+	}
+
+	// AddApprover decides the request atomically with recording the
+	// approval, so that if two distinct approvers cross Required at
+	// nearly the same time, only one of them sees crossed==true and goes
+	// on to audit/deploy.
+	req, crossed, err := c.store.AddApprover(id, approver, time.Now())
+	if err != nil {
+		return err
+	}
+	if !crossed {
+		if req.Outcome != "" {
+			// Another concurrent approval already decided this request;
+			// that caller owns auditing and deploying it.
+			return nil
+		}
+		return ctx.Responder.Update(req.MessageTS, approvalRequestMessage(req))
+	}
+
+	if err := c.audit.Record(AuditRecord{
+		RequestID:   req.ID,
+		Requester:   req.Requester,
+		Version:     req.Version,
+		Environment: req.Environment,
+		Approvers:   req.Approvers,
+		CreatedAt:   req.CreatedAt,
+		DecidedAt:   req.DecidedAt,
+		Outcome:     OutcomeApproved,
+	}); err != nil {
+		log.Println(err)
+	}
+
+	if err := ctx.Responder.Update(req.MessageTS, approvalDecidedMessage(req, OutcomeApproved)); err != nil {
+		log.Println(err)
+	}
+
+	return startDeploy(c.deployer, c.jobs, ctx.Responder.WithChannel(req.Channel), req.Channel, req.Requester, req.Version, req.Environment)
+}
+
+// handleDeny records id as denied by the clicking user, if they're
+// authorized, and leaves the deploy unstarted.
+func (c *ApprovalCoordinator) handleDeny(ctx *ActionContext, id string) error {
+	denier := ctx.Payload.User.ID
+
+	req, err := c.store.Get(id)
+	if err != nil {
+		return err
+	}
+	if req.Outcome != "" {
+		return nil
+	}
+
+	allowed, err := c.approvers.Approvers(context.Background())
+	if err != nil {
+		return err
+	}
+	if !allowed[denier] {
+		return ctx.Responder.PostEphemeral(slack.MsgOptionText(
+			fmt.Sprintf("<@%s> isn't authorized to deny deploys.", denier), false))
+	}
+
+	decidedAt := time.Now()
+	if err := c.store.SetOutcome(id, OutcomeDenied, decidedAt); err != nil {
+		return err
+	}
+	if err := c.audit.Record(AuditRecord{
+		RequestID:   req.ID,
+		Requester:   req.Requester,
+		Version:     req.Version,
+		Environment: req.Environment,
+		Approvers:   req.Approvers,
+		CreatedAt:   req.CreatedAt,
+		DecidedAt:   decidedAt,
+		Outcome:     OutcomeDenied,
+	}); err != nil {
+		log.Println(err)
+	}
+
+	return ctx.Responder.Update(req.MessageTS, approvalDecidedMessage(req, OutcomeDenied))
+}
+
+func approvalRequestMessage(req *ApprovalRequest) slack.MsgOption {
+	text := slack.NewTextBlockObject(slack.MarkdownType,
+		fmt.Sprintf("<@%s> wants to deploy `%s` to `%s`. Needs %d approval(s), has %d: %s",
+			req.Requester, req.Version, req.Environment, req.Required, len(req.Approvers), approversList(req.Approvers)), false, false)
+	blocks := []slack.Block{slack.NewSectionBlock(text, nil, nil)}
+
+	approveButtonText := slack.NewTextBlockObject(slack.PlainTextType, "Approve", false, false)
+	approveButton := slack.NewButtonBlockElement("", "approve:"+req.ID, approveButtonText)
+	approveButton.WithStyle(slack.StylePrimary)
+
+	denyButtonText := slack.NewTextBlockObject(slack.PlainTextType, "Deny", false, false)
+	denyButton := slack.NewButtonBlockElement("", "deny:"+req.ID, denyButtonText)
+	denyButton.WithStyle(slack.StyleDanger)
+
+	blocks = append(blocks, slack.NewActionBlock(deployDecisionAction, approveButton, denyButton))
+
+	return slack.MsgOptionBlocks(blocks...)
+}
+
+func approvalDecidedMessage(req *ApprovalRequest, outcome ApprovalOutcome) slack.MsgOption {
+	var text *slack.TextBlockObject
+	switch outcome {
+	case OutcomeDenied:
+		text = slack.NewTextBlockObject(slack.MarkdownType,
+			fmt.Sprintf("Deploy of `%s` to `%s` requested by <@%s> was denied.", req.Version, req.Environment, req.Requester), false, false)
+	default:
+		text = slack.NewTextBlockObject(slack.MarkdownType,
+			fmt.Sprintf("Deploy of `%s` to `%s` requested by <@%s> was %s by %s.",
+				req.Version, req.Environment, req.Requester, outcome, approversList(req.Approvers)), false, false)
+	}
+	return slack.MsgOptionBlocks(slack.NewSectionBlock(text, nil, nil))
+}
+
+func approversList(approvers []string) string {
+	if len(approvers) == 0 {
+		return "none yet"
+	}
+	mentions := make([]string, len(approvers))
+	for i, a := range approvers {
+		mentions[i] = fmt.Sprintf("<@%s>", a)
+	}
+	return strings.Join(mentions, ", ")
+}
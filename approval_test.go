@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+// fakeResponder is an in-memory Responder for exercising handlers without
+// talking to the Slack API.
+type fakeResponder struct {
+	mu       sync.Mutex
+	channel  string
+	updates  []string
+	posts    int
+	deletes  int
+	ephemera int
+}
+
+func (r *fakeResponder) PostEphemeral(...slack.MsgOption) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ephemera++
+	return nil
+}
+
+func (r *fakeResponder) ReplaceOriginal(...slack.MsgOption) error { return nil }
+
+func (r *fakeResponder) DeleteOriginal() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deletes++
+	return nil
+}
+
+func (r *fakeResponder) ReplyInThread(...slack.MsgOption) error { return nil }
+
+func (r *fakeResponder) Post(...slack.MsgOption) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.posts++
+	return "ts-1", nil
+}
+
+func (r *fakeResponder) Update(ts string, _ ...slack.MsgOption) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.updates = append(r.updates, ts)
+	return nil
+}
+
+func (r *fakeResponder) OpenView(string, slack.ModalViewRequest) error { return nil }
+
+func (r *fakeResponder) WithChannel(channel string) Responder {
+	return &fakeResponder{channel: channel}
+}
+
+// fakeApproverSource authorizes exactly the users listed.
+type fakeApproverSource map[string]bool
+
+func (s fakeApproverSource) Approvers(context.Context) (map[string]bool, error) {
+	return s, nil
+}
+
+// fakeAuditSink records every AuditRecord it's given.
+type fakeAuditSink struct {
+	mu      sync.Mutex
+	records []AuditRecord
+}
+
+func (s *fakeAuditSink) Record(rec AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, rec)
+	return nil
+}
+
+func newTestCoordinator(t *testing.T, approved fakeApproverSource, required int) (*ApprovalCoordinator, *fakeAuditSink) {
+	t.Helper()
+	audit := &fakeAuditSink{}
+	jobs := newJobLocationStore(t.TempDir() + "/deploy-jobs.json")
+	coordinator := NewApprovalCoordinator(NewMemoryStore(), approved, audit, NewNoopDeployer(), jobs, "C-approvals", required)
+	return coordinator, audit
+}
+
+func decisionContext(value, user, channel string) *ActionContext {
+	return &ActionContext{
+		Payload: &slack.InteractionCallback{
+			User:    slack.User{ID: user},
+			Channel: slack.Channel{GroupConversation: slack.GroupConversation{Conversation: slack.Conversation{ID: channel}}},
+		},
+		Action:    &slack.BlockAction{Value: value},
+		Responder: &fakeResponder{channel: channel},
+	}
+}
+
+func TestHandleDecisionApproveRequiresThreshold(t *testing.T) {
+	coordinator, audit := newTestCoordinator(t, fakeApproverSource{"U-A": true, "U-B": true, "U-C": true}, 2)
+
+	req := &ApprovalRequest{ID: "req-1", Version: "v1.0.0", Requester: "U-requester", Channel: "C1", Required: 2}
+	if err := coordinator.store.Create(req); err != nil {
+		t.Fatal(err)
+	}
+	if err := coordinator.store.SetMessageTS(req.ID, "ts-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := coordinator.HandleDecision(decisionContext("approve:req-1", "U-A", "C-approvals")); err != nil {
+		t.Fatal(err)
+	}
+	if len(audit.records) != 0 {
+		t.Fatalf("expected no audit record before the threshold is met, got %d", len(audit.records))
+	}
+
+	if err := coordinator.HandleDecision(decisionContext("approve:req-1", "U-B", "C-approvals")); err != nil {
+		t.Fatal(err)
+	}
+	if len(audit.records) != 1 {
+		t.Fatalf("expected exactly one audit record once the threshold is met, got %d", len(audit.records))
+	}
+
+	// A third, late approval must not re-decide or redeploy the request.
+	if err := coordinator.HandleDecision(decisionContext("approve:req-1", "U-C", "C-approvals")); err != nil {
+		t.Fatal(err)
+	}
+	if len(audit.records) != 1 {
+		t.Fatalf("expected the late approval not to add another audit record, got %d", len(audit.records))
+	}
+}
+
+// TestHandleDecisionConcurrentApprovalsDecideOnce exercises two distinct
+// approvers crossing Required at the same time, the way two genuinely
+// concurrent clicks would race under the HTTP transport's per-request
+// goroutines. Exactly one of them must win the decision.
+func TestHandleDecisionConcurrentApprovalsDecideOnce(t *testing.T) {
+	coordinator, audit := newTestCoordinator(t, fakeApproverSource{"U-A": true, "U-B": true}, 2)
+
+	req := &ApprovalRequest{ID: "req-1", Version: "v1.0.0", Requester: "U-requester", Channel: "C1", Required: 2}
+	if err := coordinator.store.Create(req); err != nil {
+		t.Fatal(err)
+	}
+	if err := coordinator.store.SetMessageTS(req.ID, "ts-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	approvers := []string{"U-A", "U-B"}
+	for _, approver := range approvers {
+		wg.Add(1)
+		go func(approver string) {
+			defer wg.Done()
+			if err := coordinator.HandleDecision(decisionContext("approve:req-1", approver, "C-approvals")); err != nil {
+				t.Error(err)
+			}
+		}(approver)
+	}
+	wg.Wait()
+
+	if len(audit.records) != 1 {
+		t.Fatalf("expected exactly one audit record from a concurrent threshold crossing, got %d", len(audit.records))
+	}
+}
+
+func TestHandleDecisionRejectsSelfApproval(t *testing.T) {
+	coordinator, audit := newTestCoordinator(t, fakeApproverSource{"U-requester": true}, 1)
+
+	req := &ApprovalRequest{ID: "req-1", Version: "v1.0.0", Requester: "U-requester", Channel: "C1", Required: 1}
+	if err := coordinator.store.Create(req); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := coordinator.HandleDecision(decisionContext("approve:req-1", "U-requester", "C-approvals")); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := coordinator.store.Get(req.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Approvers) != 0 {
+		t.Fatalf("expected the requester's own click not to count as an approval, got %v", got.Approvers)
+	}
+	if len(audit.records) != 0 {
+		t.Fatalf("expected no audit record from a rejected self-approval, got %d", len(audit.records))
+	}
+}
+
+func TestHandleDecisionDeny(t *testing.T) {
+	coordinator, audit := newTestCoordinator(t, fakeApproverSource{"U-A": true}, 2)
+
+	req := &ApprovalRequest{ID: "req-1", Version: "v1.0.0", Requester: "U-requester", Channel: "C1", Required: 2}
+	if err := coordinator.store.Create(req); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := coordinator.HandleDecision(decisionContext("deny:req-1", "U-A", "C-approvals")); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := coordinator.store.Get(req.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Outcome != OutcomeDenied {
+		t.Fatalf("expected outcome %q, got %q", OutcomeDenied, got.Outcome)
+	}
+	if len(audit.records) != 1 || audit.records[0].Outcome != OutcomeDenied {
+		t.Fatalf("expected one denied audit record, got %+v", audit.records)
+	}
+
+	// A decision after denial must not re-decide the request.
+	if err := coordinator.HandleDecision(decisionContext("deny:req-1", "U-A", "C-approvals")); err != nil {
+		t.Fatal(err)
+	}
+	if len(audit.records) != 1 {
+		t.Fatalf("expected the repeat deny not to add another audit record, got %d", len(audit.records))
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// ApproverSource resolves the current set of users allowed to approve a
+// deploy.
+type ApproverSource interface {
+	Approvers(ctx context.Context) (map[string]bool, error)
+}
+
+// envApproverSource reads a comma-separated list of Slack user IDs from an
+// environment variable, e.g. DEPLOY_APPROVERS=U123,U456.
+type envApproverSource struct {
+	env string
+}
+
+func newEnvApproverSource(env string) *envApproverSource {
+	return &envApproverSource{env: env}
+}
+
+func (s *envApproverSource) Approvers(_ context.Context) (map[string]bool, error) {
+	approvers := make(map[string]bool)
+	for _, id := range strings.Split(os.Getenv(s.env), ",") {
+		id = strings.TrimSpace(id)
+		if id != "" {
+			approvers[id] = true
+		}
+	}
+	return approvers, nil
+}
+
+// usergroupApproverSource resolves approvers from a Slack usergroup's
+// current membership via usergroups.users.list.
+type usergroupApproverSource struct {
+	api       *slack.Client
+	usergroup string
+}
+
+func newUsergroupApproverSource(api *slack.Client, usergroup string) *usergroupApproverSource {
+	return &usergroupApproverSource{api: api, usergroup: usergroup}
+}
+
+func (s *usergroupApproverSource) Approvers(ctx context.Context) (map[string]bool, error) {
+	members, err := s.api.GetUserGroupMembersContext(ctx, s.usergroup)
+	if err != nil {
+		return nil, err
+	}
+	approvers := make(map[string]bool, len(members))
+	for _, m := range members {
+		approvers[m] = true
+	}
+	return approvers, nil
+}
+
+// newApproverSourceFromEnv prefers a Slack usergroup (DEPLOY_APPROVER_USERGROUP)
+// when configured, so membership changes don't require redeploying the
+// bot, and otherwise falls back to the literal DEPLOY_APPROVERS user list.
+func newApproverSourceFromEnv(api *slack.Client) ApproverSource {
+	if usergroup := os.Getenv("DEPLOY_APPROVER_USERGROUP"); usergroup != "" {
+		return newUsergroupApproverSource(api, usergroup)
+	}
+	return newEnvApproverSource("DEPLOY_APPROVERS")
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditRecord is a single completed (or denied) approval's audit trail.
+type AuditRecord struct {
+	RequestID   string          `json:"request_id"`
+	Requester   string          `json:"requester"`
+	Version     string          `json:"version"`
+	Environment string          `json:"environment"`
+	Approvers   []string        `json:"approvers"`
+	CreatedAt   time.Time       `json:"created_at"`
+	DecidedAt   time.Time       `json:"decided_at"`
+	Outcome     ApprovalOutcome `json:"outcome"`
+}
+
+// AuditSink records deploy approval outcomes somewhere durable, so
+// "who approved what, and when" can be reconstructed later.
+type AuditSink interface {
+	Record(rec AuditRecord) error
+}
+
+// logAuditSink writes each record as a JSON line through the standard
+// logger. It's the default when no sink is configured.
+type logAuditSink struct{}
+
+func (logAuditSink) Record(rec AuditRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	log.Printf("[AUDIT] %s", data)
+	return nil
+}
+
+// fileAuditSink appends each record as a JSON line to a file, e.g. for a
+// log shipper to pick up.
+type fileAuditSink struct {
+	mu   sync.Mutex
+	path string
+}
+
+func newFileAuditSink(path string) *fileAuditSink {
+	return &fileAuditSink{path: path}
+}
+
+func (s *fileAuditSink) Record(rec AuditRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("audit: open %s: %w", s.path, err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// newAuditSinkFromEnv writes to DEPLOY_AUDIT_LOG_PATH when set, and falls
+// back to logging records otherwise.
+func newAuditSinkFromEnv() AuditSink {
+	if path := os.Getenv("DEPLOY_AUDIT_LOG_PATH"); path != "" {
+		return newFileAuditSink(path)
+	}
+	return logAuditSink{}
+}
@@ -1,208 +1,206 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
-	"io/ioutil"
 	"log"
-	"net/http"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/slack-go/slack"
-	"github.com/slack-go/slack/slackevents"
+
+	"github.com/satorunooshie/slack-template/messages"
 )
 
+var deployVersions = []string{"v1.0.0", "v1.1.0", "v1.1.1"}
+
 const (
 	selectVersionAction     = "select-version"
 	confirmDeploymentAction = "confirm-deployment"
+	cancelDeploymentAction  = "cancel-deployment"
 )
 
-func main() {
-	api := slack.New(os.Getenv("SLACK_BOT_TOKEN"))
+const progressPollInterval = 3 * time.Second
+
+// registerDeployHandlers wires the ephemeral-menu deploy flow onto router
+// as the "@bot deploy-quick" fallback, running jobs through deployer and
+// recording where their progress messages live in jobs. The primary
+// "@bot deploy" flow is the modal form registered by
+// registerModalDeployHandlers. Confirming a deploy doesn't start it
+// directly; coordinator gates it behind approval first.
+func registerDeployHandlers(router *Router, deployer Deployer, jobs *jobLocationStore, coordinator *ApprovalCoordinator) {
+	router.HandleMention("deploy-quick", handleDeployMention)
+	router.HandleBlockAction(selectVersionAction, handleSelectVersion)
+	router.HandleBlockAction(confirmDeploymentAction, coordinator.RequestApproval)
+	router.HandleBlockAction(deployDecisionAction, coordinator.HandleDecision)
+	router.HandleBlockAction(cancelDeploymentAction, newCancelDeploymentHandler(deployer))
+}
 
-	http.HandleFunc("/slack/events", slackVerificationMiddleware(func(w http.ResponseWriter, r *http.Request) {
-		body, err := ioutil.ReadAll(r.Body)
-		if err != nil {
-			log.Println(err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
+func handleDeployMention(ctx *MentionContext) error {
+	options, err := messages.Render("select_version.tmpl", messages.Data{Versions: deployVersions})
+	if err != nil {
+		return err
+	}
+	return ctx.Responder.PostEphemeral(options...)
+}
 
-		eventsAPIEvent, err := slackevents.ParseEvent(body, slackevents.OptionNoVerifyToken())
-		if err != nil {
-			log.Println(err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
+func handleSelectVersion(ctx *ActionContext) error {
+	version := ctx.Action.SelectedOption.Value
 
-		switch eventsAPIEvent.Type {
-		case slackevents.URLVerification:
-			var res *slackevents.ChallengeResponse
-			if err := json.Unmarshal(body, &res); err != nil {
-				log.Println(err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-			w.Header().Set("Content-Type", "text/plain")
-			if _, err := w.Write([]byte(res.Challenge)); err != nil {
-				log.Println(err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-		case slackevents.CallbackEvent:
-			innerEvent := eventsAPIEvent.InnerEvent
-			switch event := innerEvent.Data.(type) {
-			case *slackevents.AppMentionEvent:
-				message := strings.Split(event.Text, " ")
-				if len(message) < 2 {
-					w.WriteHeader(http.StatusBadRequest)
-					return
-				}
+	options, err := messages.Render("confirm_deploy.tmpl", messages.Data{Version: version})
+	if err != nil {
+		return err
+	}
+	return ctx.Responder.ReplaceOriginal(options...)
+}
 
-				command := message[1]
-				switch command {
-				case "deploy":
-					text := slack.NewTextBlockObject(slack.MarkdownType, "Please select *version*.", false, false)
-					textSection := slack.NewSectionBlock(text, nil, nil)
+// startDeploy starts version through deployer, announces it, and streams
+// its progress until the job finishes. It's shared by every flow that can
+// kick off a deploy (the ephemeral menu and the modal form).
+func startDeploy(deployer Deployer, jobs *jobLocationStore, responder Responder, channel, user, version, environment string) error {
+	jobID, err := deployer.Start(context.Background(), version, environment)
+	if err != nil {
+		failMsg := slack.MsgOptionText(
+			fmt.Sprintf("<@%s> failed to start deploying `%s` to `%s`: %s", user, version, environment, err), false)
+		return responder.ReplyInThread(failMsg)
+	}
 
-					versions := []string{"v1.0.0", "v1.1.0", "v1.1.1"}
-					options := make([]*slack.OptionBlockObject, 0, len(versions))
-					for _, v := range versions {
-						optionText := slack.NewTextBlockObject(slack.PlainTextType, v, false, false)
-						options = append(options, slack.NewOptionBlockObject(v, optionText, optionText))
-					}
+	startedOptions, err := messages.Render("deploy_started.tmpl", messages.Data{User: user, Version: version, Environment: environment})
+	if err != nil {
+		return err
+	}
+	if err := responder.ReplyInThread(startedOptions...); err != nil {
+		return err
+	}
 
-					placeholder := slack.NewTextBlockObject(slack.PlainTextType, "Select version", false, false)
-					selectMenu := slack.NewOptionsSelectBlockElement(slack.OptTypeStatic, placeholder, "", options...)
+	ts, err := responder.Post(progressMessage(user, version, StatePending, nil, jobID))
+	if err != nil {
+		return err
+	}
+	jobs.save(jobID, channel, ts, user, version)
 
-					actionBlock := slack.NewActionBlock(selectVersionAction, selectMenu)
+	go streamDeployProgress(deployer, responder, jobID, ts, user, version)
 
-					fallbackText := slack.MsgOptionText("This client is not supported.", false)
-					blocks := slack.MsgOptionBlocks(textSection, actionBlock)
+	return nil
+}
 
-					if _, err := api.PostEphemeral(event.Channel, event.User, fallbackText, blocks); err != nil {
-						log.Println(err)
-						w.WriteHeader(http.StatusInternalServerError)
-						return
-					}
-				}
+// resumeInFlightJobs re-attaches progress streaming to jobs that were still
+// running when the process last stopped, using the channel/ts/user/version
+// jobs recorded for each. Deployers that don't themselves persist job state
+// across restarts (the shipped ShellDeployer and NoopDeployer) won't
+// recognize these IDs; for those this reports that tracking was lost
+// instead of leaving the progress message stuck forever.
+func resumeInFlightJobs(api *slack.Client, deployer Deployer, jobs *jobLocationStore) {
+	for id, loc := range jobs.all() {
+		jobs.forget(id)
+
+		responder := &apiResponder{api: api, channel: loc.Channel}
+		if _, err := deployer.Status(id); err != nil {
+			lostMsg := slack.MsgOptionText(
+				fmt.Sprintf("<@%s> lost track of this deploy (`%s`) across a restart.", loc.User, id), false)
+			if err := responder.Update(loc.TS, lostMsg); err != nil {
+				log.Println(err)
 			}
+			continue
 		}
-	}))
-
-	http.HandleFunc("/slack/actions", slackVerificationMiddleware(func(w http.ResponseWriter, r *http.Request) {
-		var payload *slack.InteractionCallback
-		if err := json.Unmarshal([]byte(r.FormValue("payload")), &payload); err != nil {
-			log.Println(err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-
-		switch payload.Type {
-		case slack.InteractionTypeBlockActions:
-			if len(payload.ActionCallback.BlockActions) == 0 {
-				w.WriteHeader(http.StatusBadRequest)
-				return
-			}
-
-			action := payload.ActionCallback.BlockActions[0]
-			switch action.BlockID {
-			case selectVersionAction:
-				version := action.SelectedOption.Value
-
-				text := slack.NewTextBlockObject(slack.MarkdownType,
-					fmt.Sprintf("Could I deploy `%s`?", version), false, false)
-				textSection := slack.NewSectionBlock(text, nil, nil)
-
-				confirmButtonText := slack.NewTextBlockObject(slack.PlainTextType, "Do it", false, false)
-				confirmButton := slack.NewButtonBlockElement("", version, confirmButtonText)
-				confirmButton.WithStyle(slack.StylePrimary)
-
-				denyButtonText := slack.NewTextBlockObject(slack.PlainTextType, "Stop", false, false)
-				denyButton := slack.NewButtonBlockElement("", "deny", denyButtonText)
-				denyButton.WithStyle(slack.StyleDanger)
 
-				actionBlock := slack.NewActionBlock(confirmDeploymentAction, confirmButton, denyButton)
+		go streamDeployProgress(deployer, responder, id, loc.TS, loc.User, loc.Version)
+	}
+}
 
-				fallbackText := slack.MsgOptionText("This client is not supported.", false)
-				blocks := slack.MsgOptionBlocks(textSection, actionBlock)
+func newCancelDeploymentHandler(deployer Deployer) ActionHandler {
+	return func(ctx *ActionContext) error {
+		return deployer.Cancel(JobID(ctx.Action.Value))
+	}
+}
 
-				replaceOriginal := slack.MsgOptionReplaceOriginal(payload.ResponseURL)
-				if _, _, _, err := api.SendMessage("", replaceOriginal, fallbackText, blocks); err != nil {
-					log.Println(err)
-					w.WriteHeader(http.StatusInternalServerError)
-					return
-				}
-			case confirmDeploymentAction:
-				if strings.HasPrefix(action.Value, "v") {
-					version := action.Value
-					go func() {
-						startMsg := slack.MsgOptionText(
-							fmt.Sprintf("<@%s> OK, I will deploy `%s`.", payload.User.ID, version), false)
-						if _, _, err := api.PostMessage(payload.Channel.ID, startMsg); err != nil {
-							log.Println(err)
-						}
-
-						deploy(version)
-
-						endMsg := slack.MsgOptionText(
-							fmt.Sprintf("`%s` deployment completed!", version), false)
-						if _, _, err := api.PostMessage(payload.Channel.ID, endMsg); err != nil {
-							log.Println(err)
-						}
-					}()
-				}
+// streamDeployProgress polls deployer for jobID's state and log output,
+// updating the message at ts roughly every progressPollInterval until the
+// job reaches a terminal state.
+func streamDeployProgress(deployer Deployer, responder Responder, jobID JobID, ts, user, version string) {
+	logs, err := deployer.Logs(jobID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
 
-				deleteOriginal := slack.MsgOptionDeleteOriginal(payload.ResponseURL)
-				if _, _, _, err := api.SendMessage("", deleteOriginal); err != nil {
-					log.Println(err)
-					w.WriteHeader(http.StatusInternalServerError)
-					return
+	var tail []string
+	ticker := time.NewTicker(progressPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case line, ok := <-logs:
+			if ok {
+				tail = append(tail, line)
+				if len(tail) > 5 {
+					tail = tail[len(tail)-5:]
 				}
+				continue
 			}
+			logs = nil
+		case <-ticker.C:
 		}
-	}))
-
-	log.Println("[INFO] Server listening")
-	if err := http.ListenAndServe(":8080", nil); err != nil {
-		log.Fatal(err)
-	}
-}
 
-func slackVerificationMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		verifier, err := slack.NewSecretsVerifier(r.Header, os.Getenv("SLACK_SIGNING_SECRET"))
+		state, err := deployer.Status(jobID)
 		if err != nil {
 			log.Println(err)
-			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
-		bodyReader := io.TeeReader(r.Body, &verifier)
-		body, err := ioutil.ReadAll(bodyReader)
-		if err != nil {
+		if err := responder.Update(ts, progressMessage(user, version, state, tail, jobID)); err != nil {
 			log.Println(err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
 		}
 
-		if err := verifier.Ensure(); err != nil {
-			log.Println(err)
-			w.WriteHeader(http.StatusBadRequest)
+		switch state {
+		case StateSucceeded, StateFailed, StateCancelled:
+			completedOptions, err := messages.Render("deploy_completed.tmpl", messages.Data{Version: version, State: string(state)})
+			if err != nil {
+				log.Println(err)
+				return
+			}
+			if err := responder.ReplyInThread(completedOptions...); err != nil {
+				log.Println(err)
+			}
 			return
 		}
+	}
+}
 
-		r.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+func progressMessage(user, version string, state State, tail []string, jobID JobID) slack.MsgOption {
+	text := slack.NewTextBlockObject(slack.MarkdownType,
+		fmt.Sprintf("<@%s> deploying `%s`: %s", user, version, progressBar(state)), false, false)
+	blocks := []slack.Block{slack.NewSectionBlock(text, nil, nil)}
 
-		next.ServeHTTP(w, r)
+	if len(tail) > 0 {
+		logText := slack.NewTextBlockObject(slack.MarkdownType,
+			fmt.Sprintf("```%s```", strings.Join(tail, "\n")), false, false)
+		blocks = append(blocks, slack.NewSectionBlock(logText, nil, nil))
 	}
+
+	if state == StatePending || state == StateRunning {
+		cancelButtonText := slack.NewTextBlockObject(slack.PlainTextType, "Cancel", false, false)
+		cancelButton := slack.NewButtonBlockElement("", string(jobID), cancelButtonText)
+		cancelButton.WithStyle(slack.StyleDanger)
+		blocks = append(blocks, slack.NewActionBlock(cancelDeploymentAction, cancelButton))
+	}
+
+	return slack.MsgOptionBlocks(blocks...)
 }
 
-func deploy(version string) {
-	log.Printf("deploy %s", version)
-	time.Sleep(10 * time.Second)
+func progressBar(state State) string {
+	switch state {
+	case StatePending:
+		return "▱▱▱▱▱▱▱▱▱▱ pending"
+	case StateRunning:
+		return "▰▰▰▰▰▱▱▱▱▱ running"
+	case StateSucceeded:
+		return "▰▰▰▰▰▰▰▰▰▰ succeeded"
+	case StateFailed:
+		return "✖ failed"
+	case StateCancelled:
+		return "✖ cancelled"
+	default:
+		return string(state)
+	}
 }
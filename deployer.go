@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// JobID identifies a single deploy job started by a Deployer.
+type JobID string
+
+// State is the lifecycle state of a deploy job.
+type State string
+
+const (
+	StatePending   State = "pending"
+	StateRunning   State = "running"
+	StateSucceeded State = "succeeded"
+	StateFailed    State = "failed"
+	StateCancelled State = "cancelled"
+)
+
+// Deployer runs a deploy for a version to a target environment and reports
+// on its progress. It exists so the interactive handler doesn't need to
+// know whether a deploy is a real shell command, a call to an internal CI
+// system, or a no-op used in tests.
+type Deployer interface {
+	Start(ctx context.Context, version, environment string) (JobID, error)
+	Status(id JobID) (State, error)
+	Cancel(id JobID) error
+	// Logs returns a channel of log lines for id, closed once the job
+	// finishes.
+	Logs(id JobID) (<-chan string, error)
+}
+
+// ShellDeployer runs commandTemplate, substituting "{version}" and
+// "{environment}" with the requested version and target environment, e.g.
+// "./scripts/deploy.sh {version} {environment}".
+type ShellDeployer struct {
+	commandTemplate string
+
+	mu   sync.Mutex
+	jobs map[JobID]*shellJob
+	next int
+}
+
+func NewShellDeployer(commandTemplate string) *ShellDeployer {
+	return &ShellDeployer{
+		commandTemplate: commandTemplate,
+		jobs:            make(map[JobID]*shellJob),
+	}
+}
+
+type shellJob struct {
+	mu     sync.Mutex
+	state  State
+	cancel context.CancelFunc
+	logs   chan string
+}
+
+func (j *shellJob) setState(state State) {
+	j.mu.Lock()
+	j.state = state
+	j.mu.Unlock()
+}
+
+func (j *shellJob) getState() State {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.state
+}
+
+func (d *ShellDeployer) Start(ctx context.Context, version, environment string) (JobID, error) {
+	command := strings.ReplaceAll(d.commandTemplate, "{version}", version)
+	command = strings.ReplaceAll(command, "{environment}", environment)
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("deployer: empty command template")
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	cmd := exec.CommandContext(jobCtx, fields[0], fields[1:]...)
+
+	logs := make(chan string, 256)
+	writer := &lineWriter{lines: logs}
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+
+	job := &shellJob{state: StateRunning, cancel: cancel, logs: logs}
+
+	d.mu.Lock()
+	id := JobID(fmt.Sprintf("%s-%s-%d", environment, version, d.next))
+	d.next++
+	d.jobs[id] = job
+	d.mu.Unlock()
+
+	if err := cmd.Start(); err != nil {
+		cancel()
+		close(logs)
+		job.setState(StateFailed)
+		return "", err
+	}
+
+	go func() {
+		waitErr := cmd.Wait()
+		close(logs)
+
+		switch {
+		case jobCtx.Err() == context.Canceled:
+			job.setState(StateCancelled)
+		case waitErr != nil:
+			job.setState(StateFailed)
+		default:
+			job.setState(StateSucceeded)
+		}
+	}()
+
+	return id, nil
+}
+
+func (d *ShellDeployer) Status(id JobID) (State, error) {
+	job, ok := d.job(id)
+	if !ok {
+		return "", fmt.Errorf("deployer: unknown job %q", id)
+	}
+	return job.getState(), nil
+}
+
+func (d *ShellDeployer) Cancel(id JobID) error {
+	job, ok := d.job(id)
+	if !ok {
+		return fmt.Errorf("deployer: unknown job %q", id)
+	}
+	job.cancel()
+	return nil
+}
+
+func (d *ShellDeployer) Logs(id JobID) (<-chan string, error) {
+	job, ok := d.job(id)
+	if !ok {
+		return nil, fmt.Errorf("deployer: unknown job %q", id)
+	}
+	return job.logs, nil
+}
+
+func (d *ShellDeployer) job(id JobID) (*shellJob, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	job, ok := d.jobs[id]
+	return job, ok
+}
+
+// lineWriter splits everything written to it on newlines and delivers each
+// complete line to lines.
+type lineWriter struct {
+	mu    sync.Mutex
+	buf   []byte
+	lines chan<- string
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := string(w.buf[:i])
+		w.buf = w.buf[i+1:]
+		w.lines <- line
+	}
+	return len(p), nil
+}
+
+// NoopDeployer immediately succeeds every deploy without running anything.
+// It's intended for tests and for running the bot without a configured
+// deploy command.
+type NoopDeployer struct {
+	mu   sync.Mutex
+	jobs map[JobID]State
+	next int
+}
+
+func NewNoopDeployer() *NoopDeployer {
+	return &NoopDeployer{jobs: make(map[JobID]State)}
+}
+
+func (d *NoopDeployer) Start(_ context.Context, version, environment string) (JobID, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	id := JobID(fmt.Sprintf("%s-%s-%d", environment, version, d.next))
+	d.next++
+	d.jobs[id] = StateSucceeded
+	return id, nil
+}
+
+func (d *NoopDeployer) Status(id JobID) (State, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	state, ok := d.jobs[id]
+	if !ok {
+		return "", fmt.Errorf("deployer: unknown job %q", id)
+	}
+	return state, nil
+}
+
+func (d *NoopDeployer) Cancel(id JobID) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.jobs[id]; !ok {
+		return fmt.Errorf("deployer: unknown job %q", id)
+	}
+	d.jobs[id] = StateCancelled
+	return nil
+}
+
+func (d *NoopDeployer) Logs(id JobID) (<-chan string, error) {
+	d.mu.Lock()
+	_, ok := d.jobs[id]
+	d.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("deployer: unknown job %q", id)
+	}
+	ch := make(chan string)
+	close(ch)
+	return ch, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+)
+
+// jobLocation is where a deploy job's progress message lives, and who/what
+// it was for, so it can be found and reported on again after a restart.
+type jobLocation struct {
+	Channel string `json:"channel"`
+	TS      string `json:"ts"`
+	User    string `json:"user"`
+	Version string `json:"version"`
+}
+
+// jobLocationStore persists JobID -> jobLocation to a JSON file so a
+// restarted process can still report the final status of jobs that were
+// in flight, instead of leaving their progress messages stuck mid-deploy.
+type jobLocationStore struct {
+	mu        sync.Mutex
+	path      string
+	locations map[JobID]jobLocation
+}
+
+func newJobLocationStore(path string) *jobLocationStore {
+	s := &jobLocationStore{path: path, locations: make(map[JobID]jobLocation)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s
+	}
+	if err := json.Unmarshal(data, &s.locations); err != nil {
+		log.Printf("jobstore: discarding unreadable store at %s: %v", path, err)
+		s.locations = make(map[JobID]jobLocation)
+	}
+	return s
+}
+
+func (s *jobLocationStore) save(id JobID, channel, ts, user, version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.locations[id] = jobLocation{Channel: channel, TS: ts, User: user, Version: version}
+	s.persistLocked()
+}
+
+// all returns a snapshot of every tracked job location, e.g. to resume
+// reporting on jobs that were in flight when the process last stopped.
+func (s *jobLocationStore) all() map[JobID]jobLocation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	locations := make(map[JobID]jobLocation, len(s.locations))
+	for id, loc := range s.locations {
+		locations[id] = loc
+	}
+	return locations
+}
+
+// forget removes id, e.g. once it's been resumed or reported as lost so it
+// isn't processed again on the next restart.
+func (s *jobLocationStore) forget(id JobID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.locations, id)
+	s.persistLocked()
+}
+
+// persistLocked writes the current locations to disk. Callers must hold
+// s.mu.
+func (s *jobLocationStore) persistLocked() {
+	data, err := json.Marshal(s.locations)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if err := os.WriteFile(s.path, data, 0o644); err != nil {
+		log.Println(err)
+	}
+}
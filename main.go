@@ -0,0 +1,88 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/slack-go/slack"
+)
+
+func main() {
+	api := slack.New(os.Getenv("SLACK_BOT_TOKEN"))
+
+	router := NewRouter()
+	deployer := newDeployerFromEnv()
+	jobs := newJobLocationStore(jobLocationStorePath())
+	resumeInFlightJobs(api, deployer, jobs)
+
+	coordinator := newApprovalCoordinatorFromEnv(api, deployer, jobs)
+	registerModalDeployHandlers(router, coordinator)
+	registerDeployHandlers(router, deployer, jobs, coordinator)
+
+	transport := newTransport(api, router)
+
+	log.Println("[INFO] Server listening")
+	if err := transport.Run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// newDeployerFromEnv builds the configured Deployer. DEPLOY_COMMAND holds a
+// shell command template, e.g. "./scripts/deploy.sh {version}"; with it
+// unset the bot runs with a no-op deployer.
+func newDeployerFromEnv() Deployer {
+	if command := os.Getenv("DEPLOY_COMMAND"); command != "" {
+		return NewShellDeployer(command)
+	}
+	return NewNoopDeployer()
+}
+
+// jobLocationStorePath is where in-flight deploy jobs' channel/ts are
+// persisted, configurable via DEPLOY_JOB_STORE_PATH.
+func jobLocationStorePath() string {
+	if path := os.Getenv("DEPLOY_JOB_STORE_PATH"); path != "" {
+		return path
+	}
+	return "deploy-jobs.json"
+}
+
+// newApprovalCoordinatorFromEnv builds the ApprovalCoordinator gating
+// deploys. Approval requests are persisted to a BoltDB file when
+// DEPLOY_APPROVALS_DB_PATH is set, and kept in memory otherwise.
+// DEPLOY_APPROVAL_CHANNEL is where approval requests are posted, and
+// DEPLOY_REQUIRED_APPROVALS is how many distinct approvers a deploy needs
+// before it runs, defaulting to 1.
+func newApprovalCoordinatorFromEnv(api *slack.Client, deployer Deployer, jobs *jobLocationStore) *ApprovalCoordinator {
+	store, err := newApprovalStoreFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	required := 1
+	if n := os.Getenv("DEPLOY_REQUIRED_APPROVALS"); n != "" {
+		required, err = strconv.Atoi(n)
+		if err != nil {
+			log.Fatalf("invalid DEPLOY_REQUIRED_APPROVALS %q: %v", n, err)
+		}
+	}
+
+	return NewApprovalCoordinator(
+		store,
+		newApproverSourceFromEnv(api),
+		newAuditSinkFromEnv(),
+		deployer,
+		jobs,
+		os.Getenv("DEPLOY_APPROVAL_CHANNEL"),
+		required,
+	)
+}
+
+// newApprovalStoreFromEnv returns a BoltStore backed by DEPLOY_APPROVALS_DB_PATH
+// when set, and an in-memory MemoryStore otherwise.
+func newApprovalStoreFromEnv() (Store, error) {
+	if path := os.Getenv("DEPLOY_APPROVALS_DB_PATH"); path != "" {
+		return NewBoltStore(path)
+	}
+	return NewMemoryStore(), nil
+}
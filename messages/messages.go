@@ -0,0 +1,89 @@
+// Package messages renders Block Kit payloads from named templates, so
+// copy and branding can be changed without recompiling the bot.
+package messages
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"text/template"
+
+	"github.com/slack-go/slack"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var templates = template.Must(
+	template.New("messages").Funcs(template.FuncMap{"json": jsonEscape}).ParseFS(templateFS, "templates/*.tmpl"),
+)
+
+// jsonEscape escapes s for embedding inside a JSON string literal the
+// template itself already quotes, e.g. "...{{.User | json}}...". Plain
+// text/template interpolation doesn't escape for JSON, so a value
+// containing a '"' or '\' would otherwise corrupt, or inject into, the
+// surrounding Block Kit payload.
+func jsonEscape(s string) (string, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	return string(b[1 : len(b)-1]), nil
+}
+
+// Data is the set of values a message template may reference. Not every
+// template uses every field.
+type Data struct {
+	Version     string
+	Versions    []string
+	Environment string
+	User        string
+	Channel     string
+	State       string
+}
+
+// doc is the shape every template renders to: a Block Kit payload plus an
+// optional per-message identity override.
+type doc struct {
+	Text      string          `json:"text"`
+	Username  string          `json:"username"`
+	IconEmoji string          `json:"icon_emoji"`
+	IconURL   string          `json:"icon_url"`
+	Blocks    json.RawMessage `json:"blocks"`
+}
+
+// Render executes the named template against data and returns it as
+// slack.MsgOptions, including any username/icon override the template
+// declares.
+func Render(name string, data Data) ([]slack.MsgOption, error) {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		return nil, fmt.Errorf("messages: render %s: %w", name, err)
+	}
+
+	var d doc
+	if err := json.Unmarshal(buf.Bytes(), &d); err != nil {
+		return nil, fmt.Errorf("messages: parse %s: %w", name, err)
+	}
+
+	var blocks slack.Blocks
+	if len(d.Blocks) > 0 {
+		if err := json.Unmarshal(d.Blocks, &blocks); err != nil {
+			return nil, fmt.Errorf("messages: parse %s blocks: %w", name, err)
+		}
+	}
+
+	options := []slack.MsgOption{slack.MsgOptionText(d.Text, false), slack.MsgOptionBlocks(blocks.BlockSet...)}
+	if d.Username != "" {
+		options = append(options, slack.MsgOptionUsername(d.Username))
+	}
+	if d.IconEmoji != "" {
+		options = append(options, slack.MsgOptionIconEmoji(d.IconEmoji))
+	}
+	if d.IconURL != "" {
+		options = append(options, slack.MsgOptionIconURL(d.IconURL))
+	}
+
+	return options, nil
+}
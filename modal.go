@@ -0,0 +1,143 @@
+package main
+
+import (
+	"log"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	openDeployModalAction = "open-deploy-modal"
+	deployModalCallbackID = "deploy-modal"
+
+	deployModalVersionBlock = "version"
+	deployModalNotesBlock   = "release-notes"
+	deployModalEnvBlock     = "environment"
+	deployModalConfirmBlock = "confirm"
+
+	deployModalVersionAction = "selected-version"
+	deployModalNotesAction   = "notes"
+	deployModalEnvAction     = "selected-environment"
+	deployModalConfirmAction = "confirmed"
+)
+
+// registerModalDeployHandlers wires the modal-based deploy flow onto
+// router as the primary "@bot deploy" command: the mention opens the form,
+// and submitting it requests approval the same way the ephemeral
+// "@bot deploy-quick" flow does.
+func registerModalDeployHandlers(router *Router, coordinator *ApprovalCoordinator) {
+	router.HandleMention("deploy", handleDeployMentionModal)
+	router.HandleBlockAction(openDeployModalAction, handleOpenDeployModal)
+	router.HandleViewSubmission(deployModalCallbackID, newDeploySubmissionHandler(coordinator))
+}
+
+func handleDeployMentionModal(ctx *MentionContext) error {
+	text := slack.NewTextBlockObject(slack.MarkdownType, "Let's deploy. Click below to open the deploy form.", false, false)
+	textSection := slack.NewSectionBlock(text, nil, nil)
+
+	buttonText := slack.NewTextBlockObject(slack.PlainTextType, "Open deploy form", false, false)
+	button := slack.NewButtonBlockElement("", ctx.Event.Channel, buttonText)
+	button.WithStyle(slack.StylePrimary)
+
+	actionBlock := slack.NewActionBlock(openDeployModalAction, button)
+
+	fallbackText := slack.MsgOptionText("This client is not supported.", false)
+	blocks := slack.MsgOptionBlocks(textSection, actionBlock)
+
+	return ctx.Responder.PostEphemeral(fallbackText, blocks)
+}
+
+// handleOpenDeployModal opens the deploy form. A trigger_id is only handed
+// out with an interaction payload, never with an app_mention event, which
+// is why "@bot deploy" has to post a button first instead of opening the
+// modal directly from the mention.
+func handleOpenDeployModal(ctx *ActionContext) error {
+	channel := ctx.Action.Value
+	return ctx.Responder.OpenView(ctx.TriggerID, deployModalView(channel))
+}
+
+func deployModalView(channel string) slack.ModalViewRequest {
+	options := make([]*slack.OptionBlockObject, 0, len(deployVersions))
+	for _, v := range deployVersions {
+		optionText := slack.NewTextBlockObject(slack.PlainTextType, v, false, false)
+		options = append(options, slack.NewOptionBlockObject(v, optionText, nil))
+	}
+	versionPlaceholder := slack.NewTextBlockObject(slack.PlainTextType, "Select version", false, false)
+	versionSelect := slack.NewOptionsSelectBlockElement(slack.OptTypeStatic, versionPlaceholder, deployModalVersionAction, options...)
+	versionBlock := slack.NewInputBlock(deployModalVersionBlock,
+		slack.NewTextBlockObject(slack.PlainTextType, "Version", false, false), nil, versionSelect)
+
+	notesInput := slack.NewPlainTextInputBlockElement(
+		slack.NewTextBlockObject(slack.PlainTextType, "What changed?", false, false), deployModalNotesAction)
+	notesInput.Multiline = true
+	notesBlock := slack.NewInputBlock(deployModalNotesBlock,
+		slack.NewTextBlockObject(slack.PlainTextType, "Release notes", false, false), nil, notesInput)
+	notesBlock.Optional = true
+
+	envSelect := slack.NewRadioButtonsBlockElement(deployModalEnvAction,
+		slack.NewOptionBlockObject("staging", slack.NewTextBlockObject(slack.PlainTextType, "staging", false, false), nil),
+		slack.NewOptionBlockObject("prod", slack.NewTextBlockObject(slack.PlainTextType, "prod", false, false), nil),
+	)
+	envBlock := slack.NewInputBlock(deployModalEnvBlock,
+		slack.NewTextBlockObject(slack.PlainTextType, "Environment", false, false), nil, envSelect)
+
+	confirmCheckbox := slack.NewCheckboxGroupsBlockElement(deployModalConfirmAction,
+		slack.NewOptionBlockObject("confirmed",
+			slack.NewTextBlockObject(slack.PlainTextType, "I'm sure I want to deploy this", false, false), nil),
+	)
+	confirmBlock := slack.NewInputBlock(deployModalConfirmBlock,
+		slack.NewTextBlockObject(slack.PlainTextType, "Confirm", false, false), nil, confirmCheckbox)
+
+	return slack.ModalViewRequest{
+		Type:            slack.VTModal,
+		Title:           slack.NewTextBlockObject(slack.PlainTextType, "Deploy", false, false),
+		Submit:          slack.NewTextBlockObject(slack.PlainTextType, "Deploy", false, false),
+		Close:           slack.NewTextBlockObject(slack.PlainTextType, "Cancel", false, false),
+		CallbackID:      deployModalCallbackID,
+		PrivateMetadata: channel,
+		Blocks: slack.Blocks{
+			BlockSet: []slack.Block{versionBlock, notesBlock, envBlock, confirmBlock},
+		},
+	}
+}
+
+// newDeploySubmissionHandler validates the submitted deploy form and, once
+// valid, requests approval the same way the confirm-deployment block
+// action does.
+func newDeploySubmissionHandler(coordinator *ApprovalCoordinator) ViewSubmissionHandler {
+	return func(ctx *ViewSubmissionContext) (*slack.ViewSubmissionResponse, error) {
+		values := ctx.View.State.Values
+		errs := make(map[string]string)
+
+		version := values[deployModalVersionBlock][deployModalVersionAction].SelectedOption.Value
+		if version == "" {
+			errs[deployModalVersionBlock] = "Select a version to deploy."
+		}
+
+		environment := values[deployModalEnvBlock][deployModalEnvAction].SelectedOption.Value
+		if environment == "" {
+			errs[deployModalEnvBlock] = "Select an environment."
+		}
+
+		confirmed := false
+		for _, opt := range values[deployModalConfirmBlock][deployModalConfirmAction].SelectedOptions {
+			if opt.Value == "confirmed" {
+				confirmed = true
+			}
+		}
+		if !confirmed {
+			errs[deployModalConfirmBlock] = "Check the box to confirm the deploy."
+		}
+
+		if len(errs) > 0 {
+			return slack.NewErrorsViewSubmissionResponse(errs), nil
+		}
+
+		notes := values[deployModalNotesBlock][deployModalNotesAction].Value
+		user := ctx.Payload.User.ID
+		log.Printf("deploy modal submission: user=%s version=%s environment=%s notes=%q", user, version, environment, notes)
+
+		err := coordinator.RequestModalApproval(ctx, user, version, environment)
+		return nil, err
+	}
+}
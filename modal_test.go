@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func viewSubmissionContext(values map[string]map[string]slack.BlockAction, privateMetadata, user string) *ViewSubmissionContext {
+	return &ViewSubmissionContext{
+		Payload: &slack.InteractionCallback{
+			User: slack.User{ID: user},
+			View: slack.View{State: &slack.ViewState{Values: values}},
+		},
+		View:      slack.View{PrivateMetadata: privateMetadata, State: &slack.ViewState{Values: values}},
+		Responder: &fakeResponder{channel: privateMetadata},
+	}
+}
+
+func confirmedValues(version, environment string, confirmed bool) map[string]map[string]slack.BlockAction {
+	values := map[string]map[string]slack.BlockAction{
+		deployModalVersionBlock: {
+			deployModalVersionAction: {SelectedOption: slack.OptionBlockObject{Value: version}},
+		},
+		deployModalEnvBlock: {
+			deployModalEnvAction: {SelectedOption: slack.OptionBlockObject{Value: environment}},
+		},
+		deployModalNotesBlock: {
+			deployModalNotesAction: {Value: "notes"},
+		},
+		deployModalConfirmBlock: {
+			deployModalConfirmAction: {},
+		},
+	}
+	if confirmed {
+		values[deployModalConfirmBlock][deployModalConfirmAction] = slack.BlockAction{
+			SelectedOptions: []slack.OptionBlockObject{{Value: "confirmed"}},
+		}
+	}
+	return values
+}
+
+func TestDeploySubmissionHandlerValidation(t *testing.T) {
+	coordinator, _ := newTestCoordinator(t, fakeApproverSource{}, 1)
+	handler := newDeploySubmissionHandler(coordinator)
+
+	cases := []struct {
+		name        string
+		version     string
+		environment string
+		confirmed   bool
+		wantErrs    []string
+	}{
+		{"missing everything", "", "", false, []string{deployModalVersionBlock, deployModalEnvBlock, deployModalConfirmBlock}},
+		{"missing confirm", "v1.0.0", "staging", false, []string{deployModalConfirmBlock}},
+		{"valid", "v1.0.0", "staging", true, nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := viewSubmissionContext(confirmedValues(c.version, c.environment, c.confirmed), "C1", "U1")
+			resp, err := handler(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(c.wantErrs) == 0 {
+				if resp != nil {
+					t.Fatalf("expected a valid submission to be accepted, got errors: %v", resp.Errors)
+				}
+				return
+			}
+
+			if resp == nil {
+				t.Fatalf("expected validation errors %v, got none", c.wantErrs)
+			}
+			for _, block := range c.wantErrs {
+				if _, ok := resp.Errors[block]; !ok {
+					t.Errorf("expected an error on block %q, got %v", block, resp.Errors)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/slack-go/slack"
+)
+
+// Responder abstracts the ways a handler can reply to the Slack surface
+// that triggered it, so handlers don't need to know whether they were
+// invoked from an ephemeral menu, a button click, or a mention.
+type Responder interface {
+	// PostEphemeral posts a message only the triggering user can see.
+	PostEphemeral(options ...slack.MsgOption) error
+	// ReplaceOriginal replaces the message that triggered the interaction.
+	ReplaceOriginal(options ...slack.MsgOption) error
+	// DeleteOriginal deletes the message that triggered the interaction.
+	DeleteOriginal() error
+	// ReplyInThread posts a message as a threaded reply.
+	ReplyInThread(options ...slack.MsgOption) error
+	// Post sends a new, standalone message and returns its timestamp so a
+	// handler can later update it, e.g. with Update.
+	Post(options ...slack.MsgOption) (ts string, err error)
+	// Update edits the message at ts in place, e.g. to report progress.
+	Update(ts string, options ...slack.MsgOption) error
+	// OpenView opens a modal in response to a trigger (a mention's
+	// follow-up button click, a slash command, ...).
+	OpenView(triggerID string, view slack.ModalViewRequest) error
+	// WithChannel returns a Responder bound to a different channel, e.g.
+	// to reply somewhere other than where the interaction happened.
+	WithChannel(channel string) Responder
+}
+
+// apiResponder implements Responder on top of a slack.Client plus the
+// channel/user/response URL/thread context of a single event.
+type apiResponder struct {
+	api         *slack.Client
+	channel     string
+	user        string
+	responseURL string
+	threadTS    string
+}
+
+func (r *apiResponder) PostEphemeral(options ...slack.MsgOption) error {
+	_, err := r.api.PostEphemeral(r.channel, r.user, options...)
+	return err
+}
+
+func (r *apiResponder) ReplaceOriginal(options ...slack.MsgOption) error {
+	if r.responseURL == "" {
+		return fmt.Errorf("no response URL available to replace the original message")
+	}
+	options = append(options, slack.MsgOptionReplaceOriginal(r.responseURL))
+	_, _, _, err := r.api.SendMessage("", options...)
+	return err
+}
+
+func (r *apiResponder) DeleteOriginal() error {
+	if r.responseURL == "" {
+		return fmt.Errorf("no response URL available to delete the original message")
+	}
+	_, _, _, err := r.api.SendMessage("", slack.MsgOptionDeleteOriginal(r.responseURL))
+	return err
+}
+
+func (r *apiResponder) ReplyInThread(options ...slack.MsgOption) error {
+	if r.threadTS != "" {
+		options = append(options, slack.MsgOptionTS(r.threadTS))
+	}
+	_, _, err := r.api.PostMessage(r.channel, options...)
+	return err
+}
+
+func (r *apiResponder) Post(options ...slack.MsgOption) (string, error) {
+	_, ts, err := r.api.PostMessage(r.channel, options...)
+	return ts, err
+}
+
+func (r *apiResponder) Update(ts string, options ...slack.MsgOption) error {
+	_, _, _, err := r.api.UpdateMessage(r.channel, ts, options...)
+	return err
+}
+
+func (r *apiResponder) OpenView(triggerID string, view slack.ModalViewRequest) error {
+	_, err := r.api.OpenView(triggerID, view)
+	return err
+}
+
+func (r *apiResponder) WithChannel(channel string) Responder {
+	clone := *r
+	clone.channel = channel
+	clone.responseURL = ""
+	clone.threadTS = ""
+	return &clone
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// MentionContext carries the parsed app_mention event that triggered a
+// MentionHandler, along with the words following the bot's own mention.
+type MentionContext struct {
+	Event     *slackevents.AppMentionEvent
+	Args      []string
+	Responder Responder
+}
+
+// ActionContext carries the interaction callback and the specific block
+// action that triggered an ActionHandler.
+type ActionContext struct {
+	Payload   *slack.InteractionCallback
+	Action    *slack.BlockAction
+	TriggerID string
+	Responder Responder
+}
+
+// ViewSubmissionContext carries the interaction callback for a submitted
+// modal view.
+type ViewSubmissionContext struct {
+	Payload   *slack.InteractionCallback
+	View      slack.View
+	Responder Responder
+}
+
+// CommandContext carries the slash command that triggered a CommandHandler.
+type CommandContext struct {
+	Command   *slack.SlashCommand
+	Responder Responder
+}
+
+type MentionHandler func(ctx *MentionContext) error
+type ActionHandler func(ctx *ActionContext) error
+type CommandHandler func(ctx *CommandContext) error
+
+// ViewSubmissionHandler handles a modal submission. Returning a non-nil
+// *slack.ViewSubmissionResponse (e.g. via slack.NewErrorsViewSubmissionResponse)
+// tells Slack to re-render the modal with validation errors instead of
+// closing it.
+type ViewSubmissionHandler func(ctx *ViewSubmissionContext) (*slack.ViewSubmissionResponse, error)
+
+// Router dispatches mentions, block actions, slash commands, and view
+// submissions to registered handlers, so ChatOps commands can be added
+// without editing the transport or main wiring.
+type Router struct {
+	mentions        map[string]MentionHandler
+	blockActions    map[string]ActionHandler
+	commands        map[string]CommandHandler
+	viewSubmissions map[string]ViewSubmissionHandler
+}
+
+func NewRouter() *Router {
+	return &Router{
+		mentions:        make(map[string]MentionHandler),
+		blockActions:    make(map[string]ActionHandler),
+		commands:        make(map[string]CommandHandler),
+		viewSubmissions: make(map[string]ViewSubmissionHandler),
+	}
+}
+
+// HandleMention registers fn to run when a mention's first word is keyword,
+// e.g. "@bot deploy".
+func (r *Router) HandleMention(keyword string, fn MentionHandler) {
+	r.mentions[keyword] = fn
+}
+
+// HandleBlockAction registers fn to run for block actions with the given
+// BlockID.
+func (r *Router) HandleBlockAction(blockID string, fn ActionHandler) {
+	r.blockActions[blockID] = fn
+}
+
+// HandleViewSubmission registers fn to run when a modal with the given
+// CallbackID is submitted.
+func (r *Router) HandleViewSubmission(callbackID string, fn ViewSubmissionHandler) {
+	r.viewSubmissions[callbackID] = fn
+}
+
+// HandleCommand registers fn to run for the given slash command, e.g.
+// "/deploy". No transport currently dispatches a slash command, so this
+// has no caller yet; it's kept so the Router exposes the same handler
+// kinds Slack supports, ready for whichever transport wires one up first.
+func (r *Router) HandleCommand(slashCmd string, fn CommandHandler) {
+	r.commands[slashCmd] = fn
+}
+
+// DispatchMention parses an app_mention event and runs the handler
+// registered for its first word, if any.
+func (r *Router) DispatchMention(api *slack.Client, event *slackevents.AppMentionEvent) error {
+	words := strings.Split(event.Text, " ")
+	if len(words) < 2 {
+		return fmt.Errorf("unrecognized mention: %q", event.Text)
+	}
+
+	keyword := words[1]
+	fn, ok := r.mentions[keyword]
+	if !ok {
+		return nil
+	}
+
+	ctx := &MentionContext{
+		Event: event,
+		Args:  words[2:],
+		Responder: &apiResponder{
+			api:     api,
+			channel: event.Channel,
+			user:    event.User,
+		},
+	}
+	return fn(ctx)
+}
+
+// DispatchBlockAction parses a block_actions interaction callback and runs
+// the handler registered for the triggered action's BlockID.
+func (r *Router) DispatchBlockAction(api *slack.Client, payload *slack.InteractionCallback) error {
+	if len(payload.ActionCallback.BlockActions) == 0 {
+		return fmt.Errorf("interaction callback with no block actions")
+	}
+
+	action := payload.ActionCallback.BlockActions[0]
+	fn, ok := r.blockActions[action.BlockID]
+	if !ok {
+		return nil
+	}
+
+	ctx := &ActionContext{
+		Payload:   payload,
+		Action:    action,
+		TriggerID: payload.TriggerID,
+		Responder: &apiResponder{
+			api:         api,
+			channel:     payload.Channel.ID,
+			user:        payload.User.ID,
+			responseURL: payload.ResponseURL,
+		},
+	}
+	return fn(ctx)
+}
+
+// DispatchCommand runs the handler registered for a slash command, if any.
+func (r *Router) DispatchCommand(api *slack.Client, cmd *slack.SlashCommand) error {
+	fn, ok := r.commands[cmd.Command]
+	if !ok {
+		return nil
+	}
+
+	ctx := &CommandContext{
+		Command: cmd,
+		Responder: &apiResponder{
+			api:         api,
+			channel:     cmd.ChannelID,
+			user:        cmd.UserID,
+			responseURL: cmd.ResponseURL,
+		},
+	}
+	return fn(ctx)
+}
+
+// DispatchViewSubmission runs the handler registered for the submitted
+// view's CallbackID, if any.
+func (r *Router) DispatchViewSubmission(api *slack.Client, payload *slack.InteractionCallback) (*slack.ViewSubmissionResponse, error) {
+	fn, ok := r.viewSubmissions[payload.View.CallbackID]
+	if !ok {
+		return nil, nil
+	}
+
+	// The originating channel isn't part of a view submission payload, so
+	// handlers that open this modal must stash it in PrivateMetadata.
+	ctx := &ViewSubmissionContext{
+		Payload: payload,
+		View:    payload.View,
+		Responder: &apiResponder{
+			api:     api,
+			channel: payload.View.PrivateMetadata,
+			user:    payload.User.ID,
+		},
+	}
+	return fn(ctx)
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ApprovalOutcome is the final disposition of an approval request, or the
+// empty string while it's still pending.
+type ApprovalOutcome string
+
+const (
+	OutcomeApproved ApprovalOutcome = "approved"
+	OutcomeDenied   ApprovalOutcome = "denied"
+)
+
+// ApprovalRequest tracks a single deploy awaiting approval.
+type ApprovalRequest struct {
+	ID          string
+	Version     string
+	Environment string
+	Requester   string
+	Channel     string // where to report back once the deploy finishes
+	MessageTS   string // the approval request message, for progress updates
+	Required    int
+	Approvers   []string
+	CreatedAt   time.Time
+	Outcome     ApprovalOutcome
+	DecidedAt   time.Time
+}
+
+func (r *ApprovalRequest) hasApproved(user string) bool {
+	for _, a := range r.Approvers {
+		if a == user {
+			return true
+		}
+	}
+	return false
+}
+
+// Store persists approval requests, so which approvals a pending deploy
+// already has survives a restart.
+type Store interface {
+	Create(req *ApprovalRequest) error
+	Get(id string) (*ApprovalRequest, error)
+	// AddApprover records user as having approved id, and atomically
+	// decides the request (setting Outcome to OutcomeApproved, with
+	// decidedAt) if this call is the one that brings the approver count
+	// to Required. It returns the updated request and whether this call
+	// was the one that crossed the threshold. Two concurrent callers
+	// crossing Required at once must not both get crossed=true — callers
+	// should only audit/deploy when crossed is true, so the decision is
+	// made exactly once no matter how many approvers race to the last
+	// slot. Recording the same user twice is a no-op.
+	AddApprover(id, user string, decidedAt time.Time) (req *ApprovalRequest, crossed bool, err error)
+	SetOutcome(id string, outcome ApprovalOutcome, decidedAt time.Time) error
+	// SetMessageTS records where the approval request message was
+	// posted, once it's known.
+	SetMessageTS(id, ts string) error
+}
+
+// MemoryStore keeps approval requests in process memory. It's the default
+// for tests and single-instance setups that don't need approvals to
+// survive a restart.
+type MemoryStore struct {
+	mu       sync.Mutex
+	requests map[string]*ApprovalRequest
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{requests: make(map[string]*ApprovalRequest)}
+}
+
+func (s *MemoryStore) Create(req *ApprovalRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.requests[req.ID]; exists {
+		return fmt.Errorf("store: approval request %q already exists", req.ID)
+	}
+	clone := *req
+	s.requests[req.ID] = &clone
+	return nil
+}
+
+func (s *MemoryStore) Get(id string) (*ApprovalRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	req, ok := s.requests[id]
+	if !ok {
+		return nil, fmt.Errorf("store: unknown approval request %q", id)
+	}
+	clone := *req
+	return &clone, nil
+}
+
+func (s *MemoryStore) AddApprover(id, user string, decidedAt time.Time) (*ApprovalRequest, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	req, ok := s.requests[id]
+	if !ok {
+		return nil, false, fmt.Errorf("store: unknown approval request %q", id)
+	}
+	if !req.hasApproved(user) {
+		req.Approvers = append(req.Approvers, user)
+	}
+	crossed := false
+	if req.Outcome == "" && len(req.Approvers) >= req.Required {
+		req.Outcome = OutcomeApproved
+		req.DecidedAt = decidedAt
+		crossed = true
+	}
+	clone := *req
+	return &clone, crossed, nil
+}
+
+func (s *MemoryStore) SetOutcome(id string, outcome ApprovalOutcome, decidedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	req, ok := s.requests[id]
+	if !ok {
+		return fmt.Errorf("store: unknown approval request %q", id)
+	}
+	req.Outcome = outcome
+	req.DecidedAt = decidedAt
+	return nil
+}
+
+func (s *MemoryStore) SetMessageTS(id, ts string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	req, ok := s.requests[id]
+	if !ok {
+		return fmt.Errorf("store: unknown approval request %q", id)
+	}
+	req.MessageTS = ts
+	return nil
+}
+
+// BoltStore persists approval requests to a BoltDB file, so they survive a
+// restart.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+var approvalsBucket = []byte("approvals")
+
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("store: open %s: %w", path, err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(approvalsBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStore{db: db}, nil
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *BoltStore) Create(req *ApprovalRequest) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(approvalsBucket)
+		if b.Get([]byte(req.ID)) != nil {
+			return fmt.Errorf("store: approval request %q already exists", req.ID)
+		}
+		data, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(req.ID), data)
+	})
+}
+
+func (s *BoltStore) Get(id string) (*ApprovalRequest, error) {
+	var req ApprovalRequest
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(approvalsBucket).Get([]byte(id))
+		if data == nil {
+			return fmt.Errorf("store: unknown approval request %q", id)
+		}
+		return json.Unmarshal(data, &req)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+func (s *BoltStore) AddApprover(id, user string, decidedAt time.Time) (*ApprovalRequest, bool, error) {
+	var req ApprovalRequest
+	crossed := false
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(approvalsBucket)
+		data := b.Get([]byte(id))
+		if data == nil {
+			return fmt.Errorf("store: unknown approval request %q", id)
+		}
+		if err := json.Unmarshal(data, &req); err != nil {
+			return err
+		}
+		if !req.hasApproved(user) {
+			req.Approvers = append(req.Approvers, user)
+		}
+		if req.Outcome == "" && len(req.Approvers) >= req.Required {
+			req.Outcome = OutcomeApproved
+			req.DecidedAt = decidedAt
+			crossed = true
+		}
+		updated, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(id), updated)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return &req, crossed, nil
+}
+
+func (s *BoltStore) SetOutcome(id string, outcome ApprovalOutcome, decidedAt time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(approvalsBucket)
+		data := b.Get([]byte(id))
+		if data == nil {
+			return fmt.Errorf("store: unknown approval request %q", id)
+		}
+		var req ApprovalRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return err
+		}
+		req.Outcome = outcome
+		req.DecidedAt = decidedAt
+		updated, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(id), updated)
+	})
+}
+
+func (s *BoltStore) SetMessageTS(id, ts string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(approvalsBucket)
+		data := b.Get([]byte(id))
+		if data == nil {
+			return fmt.Errorf("store: unknown approval request %q", id)
+		}
+		var req ApprovalRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return err
+		}
+		req.MessageTS = ts
+		updated, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(id), updated)
+	})
+}
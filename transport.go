@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// Transport delivers Slack events API callbacks and block/view interactions
+// to the Router and acknowledges them as the underlying delivery mechanism
+// requires.
+type Transport interface {
+	Run() error
+}
+
+// newTransport selects the transport to run: Socket Mode when a
+// SLACK_APP_TOKEN (xapp-) is configured, so the bot can run behind a NAT or
+// firewall without exposing an HTTPS endpoint, and the HTTP Events API
+// otherwise.
+func newTransport(api *slack.Client, router *Router) Transport {
+	if appToken := os.Getenv("SLACK_APP_TOKEN"); appToken != "" {
+		socketAPI := slack.New(os.Getenv("SLACK_BOT_TOKEN"), slack.OptionAppLevelToken(appToken))
+		return &socketModeTransport{
+			api:    socketAPI,
+			router: router,
+			client: socketmode.New(socketAPI),
+		}
+	}
+	return &httpTransport{api: api, router: router}
+}
+
+// httpTransport serves the Events API and interactions payloads over HTTP,
+// verifying each request's signature via slackVerificationMiddleware.
+type httpTransport struct {
+	api    *slack.Client
+	router *Router
+}
+
+func (t *httpTransport) Run() error {
+	http.HandleFunc("/slack/events", slackVerificationMiddleware(t.handleEvents))
+	http.HandleFunc("/slack/actions", slackVerificationMiddleware(t.handleActions))
+
+	return http.ListenAndServe(":8080", nil)
+}
+
+func (t *httpTransport) handleEvents(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	eventsAPIEvent, err := slackevents.ParseEvent(body, slackevents.OptionNoVerifyToken())
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if eventsAPIEvent.Type == slackevents.URLVerification {
+		var res *slackevents.ChallengeResponse
+		if err := json.Unmarshal(body, &res); err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		if _, err := w.Write([]byte(res.Challenge)); err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if eventsAPIEvent.Type != slackevents.CallbackEvent {
+		return
+	}
+
+	if err := dispatchEventsAPIEvent(t.api, t.router, eventsAPIEvent); err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (t *httpTransport) handleActions(w http.ResponseWriter, r *http.Request) {
+	payload, err := parseInteractionCallback([]byte(r.FormValue("payload")))
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if payload.Type == slack.InteractionTypeViewSubmission {
+		resp, err := t.router.DispatchViewSubmission(t.api, payload)
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if resp == nil {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			log.Println(err)
+		}
+		return
+	}
+
+	if err := dispatchInteractionCallback(t.api, t.router, payload); err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func slackVerificationMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		verifier, err := slack.NewSecretsVerifier(r.Header, os.Getenv("SLACK_SIGNING_SECRET"))
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		bodyReader := io.TeeReader(r.Body, &verifier)
+		body, err := ioutil.ReadAll(bodyReader)
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := verifier.Ensure(); err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		r.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// socketModeTransport receives events and interactions over a Socket Mode
+// WebSocket connection, so no inbound HTTPS endpoint is required.
+type socketModeTransport struct {
+	api    *slack.Client
+	router *Router
+	client *socketmode.Client
+}
+
+func (t *socketModeTransport) Run() error {
+	go t.handle()
+	return t.client.RunContext(context.Background())
+}
+
+func (t *socketModeTransport) handle() {
+	for evt := range t.client.Events {
+		switch evt.Type {
+		case socketmode.EventTypeEventsAPI:
+			eventsAPIEvent, ok := evt.Data.(slackevents.EventsAPIEvent)
+			if !ok {
+				log.Printf("ignored unexpected EventsAPI payload: %v", evt.Data)
+				continue
+			}
+			t.client.Ack(*evt.Request)
+
+			if err := dispatchEventsAPIEvent(t.api, t.router, eventsAPIEvent); err != nil {
+				log.Println(err)
+			}
+		case socketmode.EventTypeInteractive:
+			payload, ok := evt.Data.(slack.InteractionCallback)
+			if !ok {
+				log.Printf("ignored unexpected interaction payload: %v", evt.Data)
+				continue
+			}
+
+			if payload.Type == slack.InteractionTypeViewSubmission {
+				resp, err := t.router.DispatchViewSubmission(t.api, &payload)
+				if err != nil {
+					log.Println(err)
+					t.client.Ack(*evt.Request)
+					continue
+				}
+				if resp == nil {
+					t.client.Ack(*evt.Request)
+				} else {
+					t.client.Ack(*evt.Request, resp)
+				}
+				continue
+			}
+
+			t.client.Ack(*evt.Request)
+			if err := dispatchInteractionCallback(t.api, t.router, &payload); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+}
+
+// parseInteractionCallback unmarshals the raw `payload` form value shared by
+// both the HTTP actions endpoint and Socket Mode interaction events.
+func parseInteractionCallback(raw []byte) (*slack.InteractionCallback, error) {
+	var payload *slack.InteractionCallback
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// dispatchEventsAPIEvent routes a parsed Events API payload through the
+// Router. It is shared by both transports so they only differ in how the
+// payload is received and acknowledged.
+func dispatchEventsAPIEvent(api *slack.Client, router *Router, eventsAPIEvent slackevents.EventsAPIEvent) error {
+	if eventsAPIEvent.Type != slackevents.CallbackEvent {
+		return nil
+	}
+
+	switch event := eventsAPIEvent.InnerEvent.Data.(type) {
+	case *slackevents.AppMentionEvent:
+		return router.DispatchMention(api, event)
+	}
+
+	return nil
+}
+
+// dispatchInteractionCallback routes a parsed interaction payload through
+// the Router.
+func dispatchInteractionCallback(api *slack.Client, router *Router, payload *slack.InteractionCallback) error {
+	switch payload.Type {
+	case slack.InteractionTypeBlockActions:
+		return router.DispatchBlockAction(api, payload)
+	}
+
+	return nil
+}